@@ -6,10 +6,18 @@ package engine
 //
 
 import (
+	"bufio"
+	"bytes"
+	"context"
 	"fmt"
+	"io"
+	"strings"
 
 	"github.com/keybase/client/go/libkb"
 	keybase1 "github.com/keybase/client/protocol/go"
+	"github.com/keybase/go-crypto/openpgp"
+	"github.com/keybase/go-crypto/openpgp/armor"
+	"github.com/keybase/go-crypto/openpgp/packet"
 )
 
 type queryType int
@@ -19,19 +27,76 @@ const (
 	fingerprint
 	kid
 	either
+	emailQuery
+	uidQuery
+	capabilityQuery
 )
 
+// PgpKeyCapability is a bitfield of the capabilities named by a
+// signature's key flags (RFC 4880 5.2.3.21). Its bit values are its own,
+// not the RFC's flag values, and both encryption flags map to
+// CapabilityEncrypt.
+type PgpKeyCapability uint8
+
+const (
+	CapabilitySign PgpKeyCapability = 1 << iota
+	CapabilityEncrypt
+	CapabilityCertify
+	CapabilityAuthenticate
+)
+
+func parseCapability(query string) PgpKeyCapability {
+	switch strings.ToLower(strings.TrimSpace(query)) {
+	case "sign":
+		return CapabilitySign
+	case "encrypt":
+		return CapabilityEncrypt
+	case "certify":
+		return CapabilityCertify
+	case "authenticate":
+		return CapabilityAuthenticate
+	default:
+		return 0
+	}
+}
+
+func capabilitiesOf(sig *packet.Signature) PgpKeyCapability {
+	if sig == nil || !sig.FlagsValid {
+		return 0
+	}
+	var caps PgpKeyCapability
+	if sig.FlagSign {
+		caps |= CapabilitySign
+	}
+	if sig.FlagEncryptCommunications || sig.FlagEncryptStorage {
+		caps |= CapabilityEncrypt
+	}
+	if sig.FlagCertify {
+		caps |= CapabilityCertify
+	}
+	if sig.FlagAuthenticate {
+		caps |= CapabilityAuthenticate
+	}
+	return caps
+}
+
 type PGPKeyExportEngine struct {
 	libkb.Contextified
-	arg   keybase1.PGPQuery
-	qtype queryType
-	res   []keybase1.KeyInfo
-	me    *libkb.User
+	arg        keybase1.PGPQuery
+	qtype      queryType
+	res        []keybase1.KeyInfo
+	me         *libkb.User
+	keyring    bool
+	keyringRes string
+	// username is set only by NewPGPKeyExportKeyringEngineForUser, for the
+	// public `/u/<name>.gpg` HTTP endpoint: it exports that user's active
+	// public keys.
+	username string
 }
 
 func (e *PGPKeyExportEngine) GetPrereqs() EnginePrereqs {
 	return EnginePrereqs{
-		Session: true,
+		Session: e.username == "",
 	}
 }
 
@@ -53,6 +118,12 @@ func (e *PGPKeyExportEngine) Results() []keybase1.KeyInfo {
 	return e.res
 }
 
+// Keyring returns the single armored keyring built by exportKeyring, for
+// engines constructed with NewPGPKeyExportKeyringEngine(ForUser).
+func (e *PGPKeyExportEngine) Keyring() string {
+	return e.keyringRes
+}
+
 func NewPGPKeyExportEngine(arg keybase1.PgpExportArg, g *libkb.GlobalContext) *PGPKeyExportEngine {
 	return &PGPKeyExportEngine{
 		arg:          arg.Options,
@@ -77,42 +148,309 @@ func NewPGPKeyExportByFingerprintEngine(arg keybase1.PgpExportByFingerprintArg,
 	}
 }
 
+// NewPGPKeyExportByEmailEngine matches against each key's identity email.
+func NewPGPKeyExportByEmailEngine(arg keybase1.PgpExportByEmailArg, g *libkb.GlobalContext) *PGPKeyExportEngine {
+	return &PGPKeyExportEngine{
+		arg:          arg.Options,
+		qtype:        emailQuery,
+		Contextified: libkb.NewContextified(g),
+	}
+}
+
+// NewPGPKeyExportByUIDEngine matches against each key's full identity UID.
+func NewPGPKeyExportByUIDEngine(arg keybase1.PgpExportByUIDArg, g *libkb.GlobalContext) *PGPKeyExportEngine {
+	return &PGPKeyExportEngine{
+		arg:          arg.Options,
+		qtype:        uidQuery,
+		Contextified: libkb.NewContextified(g),
+	}
+}
+
+// NewPGPKeyExportByCapabilityEngine matches against the sign/encrypt/
+// certify/authenticate capability bits on a signature.
+func NewPGPKeyExportByCapabilityEngine(arg keybase1.PgpExportByCapabilityArg, g *libkb.GlobalContext) *PGPKeyExportEngine {
+	return &PGPKeyExportEngine{
+		arg:          arg.Options,
+		qtype:        capabilityQuery,
+		Contextified: libkb.NewContextified(g),
+	}
+}
+
+// NewPGPKeyExportKeyringEngine builds an engine for the logged-in session
+// user that serves every one of their active public PGP keys concatenated
+// into a single armored keyring.
+func NewPGPKeyExportKeyringEngine(arg keybase1.PgpExportKeyringArg, g *libkb.GlobalContext) *PGPKeyExportEngine {
+	return &PGPKeyExportEngine{
+		arg:          arg.Options,
+		qtype:        either,
+		keyring:      true,
+		Contextified: libkb.NewContextified(g),
+	}
+}
+
+// NewPGPKeyExportKeyringEngineForUser is NewPGPKeyExportKeyringEngine for
+// the public `https://.../u/<name>.gpg` HTTP endpoint (see
+// go/service/pgp_keyring_handler.go): it serves username's active public
+// keys and doesn't require a session.
+func NewPGPKeyExportKeyringEngineForUser(username string, g *libkb.GlobalContext) *PGPKeyExportEngine {
+	e := NewPGPKeyExportKeyringEngine(keybase1.PgpExportKeyringArg{}, g)
+	e.username = username
+	return e
+}
+
 func (e *PGPKeyExportEngine) pushRes(fp libkb.PgpFingerprint, key string, desc string) {
+	e.pushMatchedRes(fp, key, desc, "", 0)
+}
+
+// pushMatchedRes is pushRes plus the UID/capability the query matched on;
+// callers without either just pass "" and 0.
+func (e *PGPKeyExportEngine) pushMatchedRes(fp libkb.PgpFingerprint, key string, desc string, matchedUID string, caps PgpKeyCapability) {
 	e.res = append(e.res, keybase1.KeyInfo{
 		Fingerprint: fp.String(),
 		Key:         key,
 		Desc:        desc,
+		MatchedUid:  matchedUID,
+		Capability:  uint8(caps),
 	})
 }
 
-func (e *PGPKeyExportEngine) exportPublic() (err error) {
-	keys := e.me.GetActivePgpKeys(false)
-	for _, k := range keys {
+// matchedKey is one of e.me's active public keys that matched the query,
+// already encoded, shared by exportPublic/exportKeyring/RunStream.
+type matchedKey struct {
+	k    *libkb.PgpKeyBundle
+	fp   libkb.PgpFingerprint
+	enc  string
+	uid  string
+	caps PgpKeyCapability
+}
+
+// matchedPublicKeys is the shared iterate/encode/match step behind
+// exportPublic, exportKeyring, and RunStream. Fingerprint and armored-key
+// encoding are k.GetFingerprintP()/k.Encode()'s responsibility, not this
+// engine's -- it just matches and collects.
+func (e *PGPKeyExportEngine) matchedPublicKeys() []matchedKey {
+	var out []matchedKey
+	for _, k := range e.me.GetActivePgpKeys(false) {
 		fp := k.GetFingerprintP()
 		s, err := k.Encode()
 		if fp == nil || err != nil {
 			continue
 		}
+
+		uid, caps := "", PgpKeyCapability(0)
 		if len(e.arg.Query) > 0 {
-			var match bool
-			switch e.qtype {
-			case either:
-				match = libkb.KeyMatchesQuery(k, e.arg.Query, e.arg.ExactMatch)
-			case fingerprint:
-				match = fp.Match(e.arg.Query, e.arg.ExactMatch)
-			case kid:
-				match = k.GetKid().Match(e.arg.Query, e.arg.ExactMatch)
-			}
-			if !match {
+			ok, u, c := e.matchQuery(k, *fp)
+			if !ok {
 				continue
 			}
+			uid, caps = u, c
 		}
-		e.pushRes(*fp, s, k.VerboseDescription())
+		out = append(out, matchedKey{k: k, fp: *fp, enc: s, uid: uid, caps: caps})
 	}
-	return
+	return out
+}
+
+func (e *PGPKeyExportEngine) exportPublic() (err error) {
+	for _, m := range e.matchedPublicKeys() {
+		e.pushMatchedRes(m.fp, m.enc, m.k.VerboseDescription(), m.uid, m.caps)
+	}
+	return nil
+}
+
+// matchQuery reports whether k matches the engine's query and, for the
+// query types where it's meaningful, which UID (email/uid) or capability
+// bits (capability) it matched on. fingerprint/kid/either queries don't
+// have a meaningful "which UID/capability" answer, so they report "", 0.
+func (e *PGPKeyExportEngine) matchQuery(k *libkb.PgpKeyBundle, fp libkb.PgpFingerprint) (matched bool, uid string, caps PgpKeyCapability) {
+	switch e.qtype {
+	case either:
+		return libkb.KeyMatchesQuery(k, e.arg.Query, e.arg.ExactMatch), "", 0
+	case fingerprint:
+		return fp.Match(e.arg.Query, e.arg.ExactMatch), "", 0
+	case kid:
+		return k.GetKid().Match(e.arg.Query, e.arg.ExactMatch), "", 0
+	case emailQuery:
+		return matchesEmail(k, e.arg.Query, e.arg.ExactMatch)
+	case uidQuery:
+		return matchesUID(k, e.arg.Query, e.arg.ExactMatch)
+	case capabilityQuery:
+		return matchesCapability(k, e.arg.Query)
+	default:
+		return false, "", 0
+	}
+}
+
+// matchesEmail matches query against an identity's email address.
+func matchesEmail(k *libkb.PgpKeyBundle, query string, exact bool) (bool, string, PgpKeyCapability) {
+	query = strings.ToLower(strings.TrimSpace(query))
+	for uid, ident := range k.Identities {
+		if ident.UserId == nil {
+			continue
+		}
+		email := strings.ToLower(ident.UserId.Email)
+		if (exact && email == query) || (!exact && strings.Contains(email, query)) {
+			return true, uid, 0
+		}
+	}
+	return false, "", 0
+}
+
+// matchesUID matches query against an identity's full UID string.
+func matchesUID(k *libkb.PgpKeyBundle, query string, exact bool) (bool, string, PgpKeyCapability) {
+	query = strings.ToLower(strings.TrimSpace(query))
+	for uid := range k.Identities {
+		lower := strings.ToLower(uid)
+		if (exact && lower == query) || (!exact && strings.Contains(lower, query)) {
+			return true, uid, 0
+		}
+	}
+	return false, "", 0
 }
 
+// matchesCapability finds a signature carrying the requested capability.
+func matchesCapability(k *libkb.PgpKeyBundle, query string) (bool, string, PgpKeyCapability) {
+	want := parseCapability(query)
+	if want == 0 {
+		return false, "", 0
+	}
+	for uid, ident := range k.Identities {
+		if caps := capabilitiesOf(ident.SelfSignature); caps&want != 0 {
+			return true, uid, caps
+		}
+	}
+	for _, sub := range k.Subkeys {
+		if caps := capabilitiesOf(sub.Sig); caps&want != 0 {
+			return true, "", caps
+		}
+	}
+	return false, "", 0
+}
+
+// RunStream is an alternative to Run/Results for large exports: it writes
+// straight to w rather than buffering KeyInfo (or the merged keyring) in
+// memory. It honors e.keyring the same way Run does, so an engine built
+// via NewPGPKeyExportKeyringEngine(ForUser) streams the same single merged
+// PUBLIC KEY BLOCK that Run+Keyring() would have built. ctx lets the
+// caller abort partway through (e.g. an HTTP client that went away).
+func (e *PGPKeyExportEngine) RunStream(ctx context.Context, w io.Writer) (err error) {
+	e.G().Log.Debug("+ PGPKeyExportEngine::RunStream")
+	defer func() {
+		e.G().Log.Debug("- PGPKeyExportEngine::RunStream -> %s", libkb.ErrToOk(err))
+	}()
+
+	if err = e.loadMe(); err != nil {
+		return err
+	}
+	if err = ctxDone(ctx); err != nil {
+		return err
+	}
+
+	keys := e.matchedPublicKeys()
+
+	if e.keyring {
+		return writeMergedKeyring(ctx, keys, w)
+	}
+
+	bw := bufio.NewWriter(w)
+	for _, m := range keys {
+		if err = ctxDone(ctx); err != nil {
+			return err
+		}
+		if _, err = bw.WriteString(m.enc); err != nil {
+			return err
+		}
+		// Flush after every key so a slow reader on the other end of
+		// w applies real backpressure.
+		if err = bw.Flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func ctxDone(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+		return nil
+	}
+}
+
+// chunkWriter adapts an io.Writer sink to a chunked RPC such as
+// PgpExportStreamArg (see go/service/pgp_export_stream_handler.go), which
+// streams the keyring out as a sequence of []byte chunks. send is called
+// once per Write with a copy of that write's bytes, so it's safe for send
+// to hold onto the slice past the call (e.g. to queue it for the RPC
+// client).
+type chunkWriter struct {
+	send func(chunk []byte) error
+}
+
+func (cw *chunkWriter) Write(p []byte) (int, error) {
+	chunk := make([]byte, len(p))
+	copy(chunk, p)
+	if err := cw.send(chunk); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// NewChunkWriter wraps a per-chunk send callback as an io.Writer suitable
+// for RunStream.
+func NewChunkWriter(send func(chunk []byte) error) io.Writer {
+	return &chunkWriter{send: send}
+}
+
+// writeMergedKeyring serializes keys into a single armored PUBLIC KEY
+// BLOCK written to w -- one armor wrapper, N packets inside. Both
+// exportKeyring and RunStream's keyring-mode path call this. ctx is
+// checked before each key, so a streaming caller can abort mid-export.
+func writeMergedKeyring(ctx context.Context, keys []matchedKey, w io.Writer) error {
+	aw, err := armor.Encode(w, openpgp.PublicKeyType, nil)
+	if err != nil {
+		return err
+	}
+	for _, m := range keys {
+		if err := ctxDone(ctx); err != nil {
+			return err
+		}
+		el, err := openpgp.ReadArmoredKeyRing(strings.NewReader(m.enc))
+		if err != nil {
+			return err
+		}
+		for _, entity := range el {
+			if err := entity.Serialize(aw); err != nil {
+				return err
+			}
+		}
+	}
+	return aw.Close()
+}
+
+// exportKeyring re-serializes e.me's matched active public keys as a
+// single armored PUBLIC KEY BLOCK. An empty set of keys still produces a
+// valid (if empty) armored block.
+func (e *PGPKeyExportEngine) exportKeyring() error {
+	var buf bytes.Buffer
+	if err := writeMergedKeyring(context.Background(), e.matchedPublicKeys(), &buf); err != nil {
+		return err
+	}
+	e.keyringRes = buf.String()
+	return nil
+}
+
+// exportSecret uses the in-process keyring by default, or gpg-agent when
+// the caller asked for it (arg.UseGPGAgent, or the "pgp.use_gpg_agent"
+// config toggle).
 func (e *PGPKeyExportEngine) exportSecret(ctx *Context) error {
+	if e.arg.UseGPGAgent || e.G().Env.GetPGPUseGPGAgent() {
+		return e.exportSecretViaBackend(ctx, libkb.NewGPGMEBackend(e.G()))
+	}
+	return e.exportSecretFromKeyring(ctx)
+}
+
+func (e *PGPKeyExportEngine) exportSecretFromKeyring(ctx *Context) error {
 	ska := libkb.SecretKeyArg{
 		Me:         e.me,
 		KeyType:    libkb.PGPKeyType,
@@ -153,7 +491,48 @@ func (e *PGPKeyExportEngine) exportSecret(ctx *Context) error {
 	return nil
 }
 
+// exportSecretViaBackend matches a fingerprint against e.me's active
+// public keys, then asks backend for the armored secret material.
+func (e *PGPKeyExportEngine) exportSecretViaBackend(ctx *Context, backend libkb.SecretKeyExportBackend) error {
+	fp := e.findActiveFingerprint()
+	if fp == nil {
+		// no match, same as the keyring path: empty result, not an error
+		return nil
+	}
+
+	ret, err := backend.ExportSecretKey(*fp, ctx.SecretUI)
+	if err != nil {
+		return err
+	}
+
+	e.pushRes(*fp, ret, "")
+	return nil
+}
+
+// findActiveFingerprint applies the engine's usual query/exact-match rules
+// against e.me's active PGP keys and returns the first match, or nil if
+// none of them match (or there's no query and the user has no keys).
+func (e *PGPKeyExportEngine) findActiveFingerprint() *libkb.PgpFingerprint {
+	for _, k := range e.me.GetActivePgpKeys(false) {
+		fp := k.GetFingerprintP()
+		if fp == nil {
+			continue
+		}
+		if len(e.arg.Query) == 0 {
+			return fp
+		}
+		if ok, _, _ := e.matchQuery(k, *fp); ok {
+			return fp
+		}
+	}
+	return nil
+}
+
 func (e *PGPKeyExportEngine) loadMe() (err error) {
+	if e.username != "" {
+		e.me, err = libkb.LoadUser(libkb.LoadUserArg{Name: e.username, PublicKeyOptional: true})
+		return
+	}
 	e.me, err = libkb.LoadMe(libkb.LoadUserArg{PublicKeyOptional: true})
 	return
 }
@@ -173,9 +552,12 @@ func (e *PGPKeyExportEngine) Run(ctx *Context) (err error) {
 		return
 	}
 
-	if e.arg.Secret {
+	switch {
+	case e.keyring:
+		err = e.exportKeyring()
+	case e.arg.Secret:
 		err = e.exportSecret(ctx)
-	} else {
+	default:
 		err = e.exportPublic()
 	}
 
@@ -0,0 +1,65 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/keybase/client/go/libkb"
+	"github.com/keybase/go-crypto/openpgp"
+)
+
+func newTestMatchedKey(t *testing.T, name, email string) matchedKey {
+	e, err := openpgp.NewEntity(name, "", email, nil)
+	if err != nil {
+		t.Fatalf("openpgp.NewEntity: %s", err)
+	}
+	bundle := libkb.NewPgpKeyBundle(e)
+	fp := bundle.GetFingerprintP()
+	enc, err := bundle.Encode()
+	if err != nil {
+		t.Fatalf("Encode: %s", err)
+	}
+	return matchedKey{k: bundle, fp: *fp, enc: enc}
+}
+
+func TestWriteMergedKeyringMergesIntoOneBlock(t *testing.T) {
+	keys := []matchedKey{
+		newTestMatchedKey(t, "Alice", "alice@example.com"),
+		newTestMatchedKey(t, "Bob", "bob@example.com"),
+	}
+
+	var buf bytes.Buffer
+	if err := writeMergedKeyring(context.Background(), keys, &buf); err != nil {
+		t.Fatalf("writeMergedKeyring: %s", err)
+	}
+
+	out := buf.String()
+	if n := strings.Count(out, "BEGIN PGP PUBLIC KEY BLOCK"); n != 1 {
+		t.Fatalf("got %d armor blocks, want 1 merged block", n)
+	}
+
+	el, err := openpgp.ReadArmoredKeyRing(strings.NewReader(out))
+	if err != nil {
+		t.Fatalf("ReadArmoredKeyRing: %s", err)
+	}
+	if len(el) != len(keys) {
+		t.Fatalf("got %d entities, want %d", len(el), len(keys))
+	}
+}
+
+func TestWriteMergedKeyringHonorsCanceledContext(t *testing.T) {
+	keys := []matchedKey{
+		newTestMatchedKey(t, "Alice", "alice@example.com"),
+		newTestMatchedKey(t, "Bob", "bob@example.com"),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var buf bytes.Buffer
+	if err := writeMergedKeyring(ctx, keys, &buf); err != context.Canceled {
+		t.Fatalf("writeMergedKeyring with canceled ctx = %v, want context.Canceled", err)
+	}
+}
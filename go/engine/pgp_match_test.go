@@ -0,0 +1,84 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/keybase/client/go/libkb"
+	keybase1 "github.com/keybase/client/protocol/go"
+	"github.com/keybase/go-crypto/openpgp"
+)
+
+func newTestBundle(t *testing.T) *libkb.PgpKeyBundle {
+	e, err := openpgp.NewEntity("Alice", "", "alice@example.com", nil)
+	if err != nil {
+		t.Fatalf("openpgp.NewEntity: %s", err)
+	}
+	return libkb.NewPgpKeyBundle(e)
+}
+
+func TestMatchQueryFingerprint(t *testing.T) {
+	bundle := newTestBundle(t)
+	fp := bundle.GetFingerprintP()
+
+	e := &PGPKeyExportEngine{arg: keybase1.PGPQuery{Query: fp.String(), ExactMatch: true}, qtype: fingerprint}
+	ok, uid, caps := e.matchQuery(bundle, *fp)
+	if !ok || uid != "" || caps != 0 {
+		t.Fatalf("matchQuery(fingerprint) = (%v, %q, %v), want (true, \"\", 0)", ok, uid, caps)
+	}
+}
+
+func TestMatchQueryKid(t *testing.T) {
+	bundle := newTestBundle(t)
+	fp := bundle.GetFingerprintP()
+	kidVal := bundle.GetKid()
+
+	e := &PGPKeyExportEngine{arg: keybase1.PGPQuery{Query: kidVal.String(), ExactMatch: true}, qtype: kid}
+	ok, _, _ := e.matchQuery(bundle, *fp)
+	if !ok {
+		t.Fatal("matchQuery(kid) = false, want true")
+	}
+}
+
+func TestMatchQueryEmail(t *testing.T) {
+	bundle := newTestBundle(t)
+	fp := bundle.GetFingerprintP()
+
+	e := &PGPKeyExportEngine{arg: keybase1.PGPQuery{Query: "alice@example.com", ExactMatch: true}, qtype: emailQuery}
+	ok, uid, _ := e.matchQuery(bundle, *fp)
+	if !ok || uid == "" {
+		t.Fatalf("matchQuery(email) = (%v, %q), want (true, non-empty)", ok, uid)
+	}
+
+	e = &PGPKeyExportEngine{arg: keybase1.PGPQuery{Query: "bob@example.com", ExactMatch: true}, qtype: emailQuery}
+	if ok, _, _ := e.matchQuery(bundle, *fp); ok {
+		t.Fatal("matchQuery(email) matched an email that isn't on the key")
+	}
+}
+
+func TestMatchQueryUID(t *testing.T) {
+	bundle := newTestBundle(t)
+	fp := bundle.GetFingerprintP()
+
+	e := &PGPKeyExportEngine{arg: keybase1.PGPQuery{Query: "Alice", ExactMatch: false}, qtype: uidQuery}
+	ok, uid, _ := e.matchQuery(bundle, *fp)
+	if !ok || uid == "" {
+		t.Fatalf("matchQuery(uid) = (%v, %q), want (true, non-empty)", ok, uid)
+	}
+}
+
+func TestMatchQueryCapability(t *testing.T) {
+	bundle := newTestBundle(t)
+	fp := bundle.GetFingerprintP()
+
+	e := &PGPKeyExportEngine{arg: keybase1.PGPQuery{Query: "sign"}, qtype: capabilityQuery}
+	ok, uid, caps := e.matchQuery(bundle, *fp)
+	if !ok || uid == "" || caps&CapabilitySign == 0 {
+		t.Fatalf("matchQuery(capability=sign) = (%v, %q, %v), want a sign match with its UID", ok, uid, caps)
+	}
+
+	e = &PGPKeyExportEngine{arg: keybase1.PGPQuery{Query: "encrypt"}, qtype: capabilityQuery}
+	ok, _, caps = e.matchQuery(bundle, *fp)
+	if !ok || caps&CapabilityEncrypt == 0 {
+		t.Fatalf("matchQuery(capability=encrypt) = (%v, _, %v), want an encrypt match", ok, caps)
+	}
+}
@@ -0,0 +1,97 @@
+package libkb
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// SecretKeyExportBackend is an external source of armored PGP secret key
+// material, such as gpg-agent. GPGMEBackend is its only implementation;
+// Keybase's own keyring is read directly, not through this interface.
+type SecretKeyExportBackend interface {
+	// ExportSecretKey returns an armored secret key for fp. secretUI
+	// prompts for a passphrase if the backend needs one.
+	ExportSecretKey(fp PgpFingerprint, secretUI SecretUI) (armored string, err error)
+}
+
+// GPGMEBackend is a SecretKeyExportBackend that shells out to the user's
+// own gpg-agent, for keys (e.g. on a smartcard/YubiKey) that never touch
+// Keybase's own keyring.
+type GPGMEBackend struct {
+	Contextified
+	gpgPath string
+}
+
+// NewGPGMEBackend returns a GPGMEBackend that invokes the `gpg` binary on
+// PATH and honors GNUPGHOME from the global config, if set.
+func NewGPGMEBackend(g *GlobalContext) *GPGMEBackend {
+	return &GPGMEBackend{Contextified: NewContextified(g)}
+}
+
+func (b *GPGMEBackend) ExportSecretKey(fp PgpFingerprint, secretUI SecretUI) (string, error) {
+	bridge := newPinentryBridge(secretUI, fmt.Sprintf("Passphrase for PGP key %s", fp.String()))
+	return b.run(bridge, "--export-secret-keys", fp)
+}
+
+func (b *GPGMEBackend) ExportPublicKey(fp PgpFingerprint) (string, error) {
+	return b.run(nil, "--export", fp)
+}
+
+func (b *GPGMEBackend) run(bridge *pinentryBridge, flag string, fp PgpFingerprint) (string, error) {
+	args := []string{flag, "--armor"}
+	if bridge != nil {
+		args = append(args, bridge.Args()...)
+	}
+	args = append(args, fp.String())
+
+	cmd := exec.Command(b.gpgBinary(), args...)
+	cmd.Env = b.env()
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if bridge != nil {
+		passphrase, err := bridge.Passphrase()
+		if err != nil {
+			return "", err
+		}
+		cmd.Stdin = strings.NewReader(passphrase + "\n")
+	}
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("gpg export failed: %s (%s)", err, stderr.String())
+	}
+	return stdout.String(), nil
+}
+
+// env builds the child's environment with GNUPGHOME from config, replacing
+// any GNUPGHOME already inherited from the ambient environment.
+func (b *GPGMEBackend) env() []string {
+	env := os.Environ()
+	home := ""
+	if g := b.G(); g != nil {
+		home = g.Env.GetGnuPGHome()
+	}
+	if home == "" {
+		return env
+	}
+
+	filtered := env[:0]
+	for _, kv := range env {
+		if !strings.HasPrefix(kv, "GNUPGHOME=") {
+			filtered = append(filtered, kv)
+		}
+	}
+	return append(filtered, "GNUPGHOME="+home)
+}
+
+func (b *GPGMEBackend) gpgBinary() string {
+	if b.gpgPath != "" {
+		return b.gpgPath
+	}
+	return "gpg"
+}
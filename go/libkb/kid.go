@@ -0,0 +1,23 @@
+package libkb
+
+import "strings"
+
+// KID is a hex-encoded key ID. Match compares it case-insensitively; when
+// exact is false, any substring of the KID matches too.
+type KID string
+
+func (k KID) String() string {
+	return string(k)
+}
+
+func (k KID) Match(query string, exact bool) bool {
+	query = strings.ToLower(strings.TrimSpace(query))
+	s := strings.ToLower(string(k))
+	if query == s {
+		return true
+	}
+	if exact {
+		return false
+	}
+	return strings.Contains(s, query)
+}
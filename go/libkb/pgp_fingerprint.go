@@ -0,0 +1,106 @@
+package libkb
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Classic v4 OpenPGP fingerprints are 160-bit (20 bytes); v5 fingerprints
+// (RFC 4880bis), used by Ed25519/Curve25519 keys, are 256-bit (32 bytes).
+const (
+	PgpFingerprintLenV4 = 20
+	PgpFingerprintLenV5 = 32
+)
+
+// PgpFingerprint holds a PGP key fingerprint. It's a variable-length byte
+// slice, not a fixed [20]byte array, so both v4 and v5 fingerprints
+// round-trip through the same type.
+type PgpFingerprint []byte
+
+func PgpFingerprintFromBytes(b []byte) *PgpFingerprint {
+	fp := PgpFingerprint(append([]byte{}, b...))
+	return &fp
+}
+
+// PgpFingerprintFromHex parses a hex-encoded fingerprint of either length.
+// It returns nil if s isn't valid hex.
+func PgpFingerprintFromHex(s string) *PgpFingerprint {
+	b, err := hex.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return nil
+	}
+	return PgpFingerprintFromBytes(b)
+}
+
+// IsV5 reports whether this is a 32-byte v5 fingerprint.
+func (p PgpFingerprint) IsV5() bool {
+	return len(p) == PgpFingerprintLenV5
+}
+
+// String renders the fingerprint as unbroken lowercase hex: 40 hex digits
+// for a v4 fingerprint, 64 for v5.
+func (p PgpFingerprint) String() string {
+	return hex.EncodeToString(p)
+}
+
+// KeyId returns the last 8 bytes (64 bits) of the fingerprint as hex, the
+// short key ID form v4 keys are traditionally queried by. v5 doesn't
+// define its own short ID, but the same suffix convention is still useful
+// for matching against old-style queries.
+func (p PgpFingerprint) KeyId() string {
+	if len(p) <= 8 {
+		return p.String()
+	}
+	return hex.EncodeToString(p[len(p)-8:])
+}
+
+// Match reports whether query (case-insensitive, whitespace-trimmed)
+// identifies this fingerprint. A full match against the v4 or v5
+// fingerprint always counts. When exact is false, a match against just
+// the trailing 64-bit key ID counts too; when exact is true, only a full
+// fingerprint match counts, so a short query can't accidentally select
+// the wrong key.
+func (p PgpFingerprint) Match(query string, exact bool) bool {
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == strings.ToLower(p.String()) {
+		return true
+	}
+	if exact {
+		return false
+	}
+	return query == strings.ToLower(p.KeyId())
+}
+
+// ComputeFingerprint computes a fingerprint directly from a public key
+// packet's body (the packet's content -- version, creation time,
+// algorithm, and key material -- not including its packet header), per
+// RFC 4880 12.2 (version 4) and RFC 4880bis 5.5.4 (version 5):
+//
+//	v4: SHA-1(0x99   || uint16(len(body)) || body)  -> 20 bytes
+//	v5: SHA-256(0x9A || uint32(len(body)) || body)  -> 32 bytes
+func ComputeFingerprint(version int, body []byte) (*PgpFingerprint, error) {
+	switch version {
+	case 4:
+		var lenField [2]byte
+		binary.BigEndian.PutUint16(lenField[:], uint16(len(body)))
+		h := sha1.New()
+		h.Write([]byte{0x99})
+		h.Write(lenField[:])
+		h.Write(body)
+		return PgpFingerprintFromBytes(h.Sum(nil)), nil
+	case 5:
+		var lenField [4]byte
+		binary.BigEndian.PutUint32(lenField[:], uint32(len(body)))
+		h := sha256.New()
+		h.Write([]byte{0x9A})
+		h.Write(lenField[:])
+		h.Write(body)
+		return PgpFingerprintFromBytes(h.Sum(nil)), nil
+	default:
+		return nil, fmt.Errorf("unsupported PGP key version: %d", version)
+	}
+}
@@ -0,0 +1,174 @@
+package libkb
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+func TestPgpFingerprintMatch(t *testing.T) {
+	v4 := PgpFingerprintFromHex("0123456789abcdef0123456789abcdef01234567")
+	v5 := PgpFingerprintFromHex("0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef")
+
+	cases := []struct {
+		fp    *PgpFingerprint
+		query string
+		exact bool
+		want  bool
+	}{
+		{v4, "0123456789ABCDEF0123456789ABCDEF01234567", true, true}, // full v4, case-insensitive
+		{v4, "89abcdef01234567", false, true},                       // v4 short key ID, non-exact
+		{v4, "89abcdef01234567", true, false},                       // short key ID rejected when exact
+		{v5, "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef", true, true}, // full v5
+		{v5, "0123456789abcdef", false, true},                       // v5 trailing 64 bits, non-exact
+		{v4, "deadbeefdeadbeef", false, false},                      // no match
+	}
+
+	for i, c := range cases {
+		if got := c.fp.Match(c.query, c.exact); got != c.want {
+			t.Errorf("case %d: Match(%q, %v) = %v, want %v", i, c.query, c.exact, got, c.want)
+		}
+	}
+}
+
+func TestPgpFingerprintIsV5(t *testing.T) {
+	v4 := PgpFingerprintFromHex("0123456789abcdef0123456789abcdef01234567")
+	v5 := PgpFingerprintFromHex("0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef")
+
+	if v4.IsV5() {
+		t.Error("20-byte fingerprint reported as v5")
+	}
+	if !v5.IsV5() {
+		t.Error("32-byte fingerprint not reported as v5")
+	}
+}
+
+// TestComputeFingerprintV4 checks the v4 construction (SHA-1 over
+// 0x99 || uint16 length || body) byte-for-byte against a hand-computed
+// hash, and that it comes out 20 bytes long as RFC 4880 12.2 requires.
+func TestComputeFingerprintV4(t *testing.T) {
+	body := []byte("fake-rsa-public-key-packet-body")
+
+	fp, err := ComputeFingerprint(4, body)
+	if err != nil {
+		t.Fatalf("ComputeFingerprint returned error: %s", err)
+	}
+	if len(*fp) != PgpFingerprintLenV4 {
+		t.Fatalf("v4 fingerprint length = %d, want %d", len(*fp), PgpFingerprintLenV4)
+	}
+
+	h := sha1.New()
+	h.Write([]byte{0x99, 0x00, byte(len(body))})
+	h.Write(body)
+	want := h.Sum(nil)
+	if fp.String() != PgpFingerprintFromBytes(want).String() {
+		t.Fatalf("ComputeFingerprint(4, ...) = %x, want %x", *fp, want)
+	}
+}
+
+// TestComputeFingerprintV5 checks the v5 construction: SHA-256 over a
+// 4-byte length field, 32 bytes long, distinct from the v4 construction.
+func TestComputeFingerprintV5(t *testing.T) {
+	body := []byte("fake-ed25519-public-key-packet-body")
+
+	fp, err := ComputeFingerprint(5, body)
+	if err != nil {
+		t.Fatalf("ComputeFingerprint returned error: %s", err)
+	}
+	if len(*fp) != PgpFingerprintLenV5 {
+		t.Fatalf("v5 fingerprint length = %d, want %d", len(*fp), PgpFingerprintLenV5)
+	}
+	if !fp.IsV5() {
+		t.Fatal("v5 fingerprint not reported as IsV5()")
+	}
+
+	h := sha256.New()
+	h.Write([]byte{0x9A, 0x00, 0x00, 0x00, byte(len(body))})
+	h.Write(body)
+	want := h.Sum(nil)
+	if fp.String() != PgpFingerprintFromBytes(want).String() {
+		t.Fatalf("ComputeFingerprint(5, ...) = %x, want %x", *fp, want)
+	}
+}
+
+func TestComputeFingerprintRejectsUnknownVersion(t *testing.T) {
+	if _, err := ComputeFingerprint(3, []byte("body")); err == nil {
+		t.Fatal("expected an error for an unsupported key version")
+	}
+}
+
+// ed25519PublicKeyPacketBody builds a v5 public-key packet body (version,
+// creation time, algorithm, curve OID, point) around an actual Ed25519
+// public key. It leaves out the four-octet key-material count that RFC
+// 4880bis 5.5.2 places after the algorithm octet.
+func ed25519PublicKeyPacketBody(pub ed25519.PublicKey, created time.Time) []byte {
+	const edDSAAlgo = 22
+	oid := []byte{0x2b, 0x06, 0x01, 0x04, 0x01, 0xda, 0x47, 0x0f, 0x01} // Ed25519 curve OID
+
+	point := append([]byte{0x40}, pub...) // native-point prefix + raw point
+
+	var body bytes.Buffer
+	body.WriteByte(5)
+	var ts [4]byte
+	binary.BigEndian.PutUint32(ts[:], uint32(created.Unix()))
+	body.Write(ts[:])
+	body.WriteByte(edDSAAlgo)
+	body.WriteByte(byte(len(oid)))
+	body.Write(oid)
+	var bitLen [2]byte
+	binary.BigEndian.PutUint16(bitLen[:], uint16(len(point)*8))
+	body.Write(bitLen[:])
+	body.Write(point)
+	return body.Bytes()
+}
+
+// TestComputeFingerprintV5Ed25519 checks ComputeFingerprint against real
+// generated Ed25519 keys: two distinct keys must produce distinct
+// fingerprints, and the fingerprint of one must match a hash computed
+// independently here.
+func TestComputeFingerprintV5Ed25519(t *testing.T) {
+	created := time.Unix(1600000000, 0)
+
+	pub1, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("ed25519.GenerateKey: %s", err)
+	}
+	pub2, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("ed25519.GenerateKey: %s", err)
+	}
+
+	body1 := ed25519PublicKeyPacketBody(pub1, created)
+	fp1, err := ComputeFingerprint(5, body1)
+	if err != nil {
+		t.Fatalf("ComputeFingerprint returned error: %s", err)
+	}
+	if !fp1.IsV5() {
+		t.Fatal("fingerprint not reported as v5")
+	}
+
+	h := sha256.New()
+	h.Write([]byte{0x9A})
+	var lenField [4]byte
+	binary.BigEndian.PutUint32(lenField[:], uint32(len(body1)))
+	h.Write(lenField[:])
+	h.Write(body1)
+	want := h.Sum(nil)
+	if fp1.String() != PgpFingerprintFromBytes(want).String() {
+		t.Fatalf("ComputeFingerprint(5, real Ed25519 body) = %x, want %x", *fp1, want)
+	}
+
+	body2 := ed25519PublicKeyPacketBody(pub2, created)
+	fp2, err := ComputeFingerprint(5, body2)
+	if err != nil {
+		t.Fatalf("ComputeFingerprint returned error: %s", err)
+	}
+	if fp1.String() == fp2.String() {
+		t.Fatal("two distinct Ed25519 keys produced the same fingerprint")
+	}
+}
@@ -0,0 +1,136 @@
+package libkb
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/keybase/go-crypto/openpgp"
+	"github.com/keybase/go-crypto/openpgp/armor"
+	"github.com/keybase/go-crypto/openpgp/packet"
+)
+
+// PgpKeyBundle wraps a parsed OpenPGP entity with the fingerprint/encoding
+// helpers PGPKeyExportEngine needs.
+type PgpKeyBundle struct {
+	*openpgp.Entity
+}
+
+func NewPgpKeyBundle(e *openpgp.Entity) *PgpKeyBundle {
+	return &PgpKeyBundle{Entity: e}
+}
+
+// GetFingerprintP computes this key's fingerprint from its primary key
+// packet's serialized body via ComputeFingerprint.
+func (k *PgpKeyBundle) GetFingerprintP() *PgpFingerprint {
+	body, version, err := publicKeyPacketBody(k.PrimaryKey)
+	if err != nil {
+		return nil
+	}
+	fp, err := ComputeFingerprint(version, body)
+	if err != nil {
+		return nil
+	}
+	return fp
+}
+
+func (k *PgpKeyBundle) GetKid() KID {
+	return KID(fmt.Sprintf("%016x", k.PrimaryKey.KeyId))
+}
+
+func (k *PgpKeyBundle) Encode() (string, error) {
+	var buf bytes.Buffer
+	aw, err := armor.Encode(&buf, openpgp.PublicKeyType, nil)
+	if err != nil {
+		return "", err
+	}
+	if err := k.Entity.Serialize(aw); err != nil {
+		return "", err
+	}
+	if err := aw.Close(); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func (k *PgpKeyBundle) VerboseDescription() string {
+	for uid := range k.Identities {
+		return uid
+	}
+	if fp := k.GetFingerprintP(); fp != nil {
+		return fp.String()
+	}
+	return ""
+}
+
+// publicKeyPacketBody serializes pk and strips its OpenPGP packet header
+// (RFC 4880 4.2), returning the body bytes ComputeFingerprint hashes and
+// the key version read off the body's first byte.
+func publicKeyPacketBody(pk *packet.PublicKey) (body []byte, version int, err error) {
+	var buf bytes.Buffer
+	if err := pk.Serialize(&buf); err != nil {
+		return nil, 0, err
+	}
+	body, err = stripPacketHeader(buf.Bytes())
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(body) == 0 {
+		return nil, 0, fmt.Errorf("empty public key packet body")
+	}
+	return body, int(body[0]), nil
+}
+
+// stripPacketHeader removes an OpenPGP packet's header (old or new format,
+// RFC 4880 4.2), returning just its body.
+func stripPacketHeader(pkt []byte) ([]byte, error) {
+	if len(pkt) == 0 {
+		return nil, fmt.Errorf("empty packet")
+	}
+	first := pkt[0]
+	if first&0x80 == 0 {
+		return nil, fmt.Errorf("not an OpenPGP packet: bad tag byte")
+	}
+
+	if first&0x40 != 0 {
+		rest := pkt[1:]
+		if len(rest) == 0 {
+			return nil, fmt.Errorf("truncated packet header")
+		}
+		switch {
+		case rest[0] < 192:
+			return rest[1:], nil
+		case rest[0] < 224:
+			if len(rest) < 2 {
+				return nil, fmt.Errorf("truncated packet header")
+			}
+			return rest[2:], nil
+		case rest[0] == 255:
+			if len(rest) < 5 {
+				return nil, fmt.Errorf("truncated packet header")
+			}
+			return rest[5:], nil
+		default:
+			return nil, fmt.Errorf("partial-length packets are not supported")
+		}
+	}
+
+	switch first & 0x03 {
+	case 0:
+		if len(pkt) < 2 {
+			return nil, fmt.Errorf("truncated packet header")
+		}
+		return pkt[2:], nil
+	case 1:
+		if len(pkt) < 3 {
+			return nil, fmt.Errorf("truncated packet header")
+		}
+		return pkt[3:], nil
+	case 2:
+		if len(pkt) < 5 {
+			return nil, fmt.Errorf("truncated packet header")
+		}
+		return pkt[5:], nil
+	default:
+		return nil, fmt.Errorf("indeterminate-length packets are not supported")
+	}
+}
@@ -0,0 +1,68 @@
+package libkb
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/keybase/go-crypto/openpgp"
+)
+
+func newTestEntity(t *testing.T) *openpgp.Entity {
+	e, err := openpgp.NewEntity("Alice", "", "alice@example.com", nil)
+	if err != nil {
+		t.Fatalf("openpgp.NewEntity: %s", err)
+	}
+	return e
+}
+
+func TestPgpKeyBundleGetFingerprintP(t *testing.T) {
+	bundle := NewPgpKeyBundle(newTestEntity(t))
+
+	fp := bundle.GetFingerprintP()
+	if fp == nil {
+		t.Fatal("GetFingerprintP returned nil")
+	}
+
+	var buf bytes.Buffer
+	if err := bundle.PrimaryKey.Serialize(&buf); err != nil {
+		t.Fatalf("PrimaryKey.Serialize: %s", err)
+	}
+	body, err := stripPacketHeader(buf.Bytes())
+	if err != nil {
+		t.Fatalf("stripPacketHeader: %s", err)
+	}
+	want, err := ComputeFingerprint(int(body[0]), body)
+	if err != nil {
+		t.Fatalf("ComputeFingerprint: %s", err)
+	}
+	if fp.String() != want.String() {
+		t.Fatalf("GetFingerprintP() = %s, want %s (independently recomputed)", fp, want)
+	}
+}
+
+func TestPgpKeyBundleEncodeRoundTrips(t *testing.T) {
+	bundle := NewPgpKeyBundle(newTestEntity(t))
+
+	armored, err := bundle.Encode()
+	if err != nil {
+		t.Fatalf("Encode: %s", err)
+	}
+
+	el, err := openpgp.ReadArmoredKeyRing(bytes.NewReader([]byte(armored)))
+	if err != nil {
+		t.Fatalf("ReadArmoredKeyRing: %s", err)
+	}
+	if len(el) != 1 {
+		t.Fatalf("got %d entities, want 1", len(el))
+	}
+	if el[0].PrimaryKey.KeyId != bundle.PrimaryKey.KeyId {
+		t.Fatal("round-tripped entity has a different primary key")
+	}
+}
+
+func TestPgpKeyBundleVerboseDescription(t *testing.T) {
+	bundle := NewPgpKeyBundle(newTestEntity(t))
+	if desc := bundle.VerboseDescription(); desc == "" {
+		t.Fatal("VerboseDescription returned empty string")
+	}
+}
@@ -0,0 +1,30 @@
+package libkb
+
+import (
+	keybase1 "github.com/keybase/client/protocol/go"
+)
+
+// pinentryBridge makes a gpg subprocess take its passphrase from SecretUI
+// via gpg's own loopback pinentry mode instead of popping a native dialog.
+type pinentryBridge struct {
+	secretUI SecretUI
+	prompt   string
+}
+
+func newPinentryBridge(secretUI SecretUI, prompt string) *pinentryBridge {
+	return &pinentryBridge{secretUI: secretUI, prompt: prompt}
+}
+
+// Args returns the gpg flags that switch it into loopback pinentry mode.
+func (p *pinentryBridge) Args() []string {
+	return []string{"--pinentry-mode", "loopback", "--batch", "--passphrase-fd", "0"}
+}
+
+// Passphrase prompts through secretUI and returns what the user entered.
+func (p *pinentryBridge) Passphrase() (string, error) {
+	res, err := p.secretUI.GetPassphrase(keybase1.GUIEntryArg{Prompt: p.prompt}, nil)
+	if err != nil {
+		return "", err
+	}
+	return res.Passphrase, nil
+}
@@ -0,0 +1,9 @@
+package libkb
+
+import keybase1 "github.com/keybase/client/protocol/go"
+
+// SecretUI is how an engine or backend prompts whatever UI is attached to
+// this session for a secret, such as a PGP passphrase.
+type SecretUI interface {
+	GetPassphrase(pinentry keybase1.GUIEntryArg, terminal *keybase1.SecretEntryArg) (keybase1.GetPassphraseRes, error)
+}
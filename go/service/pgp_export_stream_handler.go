@@ -0,0 +1,32 @@
+package service
+
+import (
+	"context"
+
+	"github.com/keybase/client/go/engine"
+	"github.com/keybase/client/go/libkb"
+	keybase1 "github.com/keybase/client/protocol/go"
+)
+
+// PgpExportStreamSender is the RPC client notification func a
+// PgpExportStream call sends its chunks through, one notification per
+// chunk rather than a single buffered reply.
+type PgpExportStreamSender interface {
+	PgpExportStream(ctx context.Context, sessionID int, chunk []byte) error
+}
+
+// HandlePgpExportStream is the PgpExportStream RPC handler: it runs a
+// keyring-mode PGPKeyExportEngine and forwards its output to sender as a
+// sequence of chunks via engine.NewChunkWriter.
+func HandlePgpExportStream(ctx context.Context, g *libkb.GlobalContext, arg keybase1.PgpExportStreamArg, sender PgpExportStreamSender) error {
+	eng := engine.NewPGPKeyExportKeyringEngine(keybase1.PgpExportKeyringArg{
+		SessionID: arg.SessionID,
+		Options:   arg.Options,
+	}, g)
+
+	w := engine.NewChunkWriter(func(chunk []byte) error {
+		return sender.PgpExportStream(ctx, arg.SessionID, chunk)
+	})
+
+	return eng.RunStream(ctx, w)
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/keybase/client/go/engine"
+	"github.com/keybase/client/go/libkb"
+)
+
+// pgpKeyringExporter is the subset of *engine.PGPKeyExportEngine
+// PGPKeyringHandler needs, so tests can inject a fake one.
+type pgpKeyringExporter interface {
+	Run(ctx *engine.Context) error
+	Keyring() string
+}
+
+// PGPKeyringHandler serves GET /u/<username>.gpg: every active public PGP
+// key that user has, concatenated into one armored keyring. No session is
+// required; the username comes straight from the URL.
+type PGPKeyringHandler struct {
+	libkb.Contextified
+	newEngine func(username string, g *libkb.GlobalContext) pgpKeyringExporter
+}
+
+func NewPGPKeyringHandler(g *libkb.GlobalContext) *PGPKeyringHandler {
+	return &PGPKeyringHandler{
+		Contextified: libkb.NewContextified(g),
+		newEngine: func(username string, g *libkb.GlobalContext) pgpKeyringExporter {
+			return engine.NewPGPKeyExportKeyringEngineForUser(username, g)
+		},
+	}
+}
+
+// gpgUsername extracts the username from a /u/<username>.gpg path, and
+// reports whether path actually has that shape.
+func gpgUsername(path string) (username string, ok bool) {
+	if !strings.HasPrefix(path, "/u/") {
+		return "", false
+	}
+	rest := strings.TrimPrefix(path, "/u/")
+	if !strings.HasSuffix(rest, ".gpg") {
+		return "", false
+	}
+	username = strings.TrimSuffix(rest, ".gpg")
+	return username, username != ""
+}
+
+func (h *PGPKeyringHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	username, ok := gpgUsername(r.URL.Path)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	eng := h.newEngine(username, h.G())
+	if err := eng.Run(&engine.Context{}); err != nil {
+		// LoadUser reports an unknown username as libkb.NotFoundError; a
+		// public, crawlable endpoint should 404 on that, not 500.
+		if _, ok := err.(libkb.NotFoundError); ok {
+			http.NotFound(w, r)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pgp-keys")
+	w.Write([]byte(eng.Keyring()))
+}
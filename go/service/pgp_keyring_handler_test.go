@@ -0,0 +1,91 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/keybase/client/go/engine"
+	"github.com/keybase/client/go/libkb"
+)
+
+func TestGpgUsername(t *testing.T) {
+	cases := []struct {
+		path     string
+		username string
+		ok       bool
+	}{
+		{"/u/alice.gpg", "alice", true},
+		{"/u/.gpg", "", false},
+		{"/u/alice", "", false},
+		{"/other/alice.gpg", "", false},
+	}
+	for _, c := range cases {
+		username, ok := gpgUsername(c.path)
+		if username != c.username || ok != c.ok {
+			t.Errorf("gpgUsername(%q) = (%q, %v), want (%q, %v)", c.path, username, ok, c.username, c.ok)
+		}
+	}
+}
+
+type fakeExporter struct {
+	err     error
+	keyring string
+}
+
+func (f fakeExporter) Run(ctx *engine.Context) error { return f.err }
+func (f fakeExporter) Keyring() string               { return f.keyring }
+
+func newTestHandler(exp pgpKeyringExporter) *PGPKeyringHandler {
+	return &PGPKeyringHandler{
+		newEngine: func(username string, g *libkb.GlobalContext) pgpKeyringExporter {
+			return exp
+		},
+	}
+}
+
+func TestPGPKeyringHandlerBadPath(t *testing.T) {
+	h := newTestHandler(fakeExporter{})
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/u/alice", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want %d", rr.Code, http.StatusNotFound)
+	}
+}
+
+func TestPGPKeyringHandlerUnknownUser404s(t *testing.T) {
+	h := newTestHandler(fakeExporter{err: libkb.NotFoundError{Msg: "no such user"}})
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/u/nobody.gpg", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want %d", rr.Code, http.StatusNotFound)
+	}
+}
+
+func TestPGPKeyringHandlerOtherErrorIs500(t *testing.T) {
+	h := newTestHandler(fakeExporter{err: fmtError("boom")})
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/u/alice.gpg", nil))
+	if rr.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want %d", rr.Code, http.StatusInternalServerError)
+	}
+}
+
+func TestPGPKeyringHandlerSuccess(t *testing.T) {
+	h := newTestHandler(fakeExporter{keyring: "-----BEGIN PGP PUBLIC KEY BLOCK-----\nstub\n-----END PGP PUBLIC KEY BLOCK-----"})
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/u/alice.gpg", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rr.Code, http.StatusOK)
+	}
+	if ct := rr.Header().Get("Content-Type"); ct != "application/pgp-keys" {
+		t.Fatalf("Content-Type = %q, want application/pgp-keys", ct)
+	}
+	if rr.Body.String() == "" {
+		t.Fatal("empty body")
+	}
+}
+
+type fmtError string
+
+func (e fmtError) Error() string { return string(e) }
@@ -0,0 +1,70 @@
+package keybase1
+
+// PGPQuery identifies which of a user's PGP keys an export engine should
+// act on.
+type PGPQuery struct {
+	Query       string `codec:"query" json:"query"`
+	ExactMatch  bool   `codec:"exactMatch" json:"exactMatch"`
+	Secret      bool   `codec:"secret" json:"secret"`
+	UseGPGAgent bool   `codec:"useGPGAgent" json:"useGPGAgent"`
+}
+
+// KeyInfo describes one exported PGP key.
+type KeyInfo struct {
+	Fingerprint string `codec:"fingerprint" json:"fingerprint"`
+	Key         string `codec:"key" json:"key"`
+	Desc        string `codec:"desc" json:"desc"`
+	MatchedUid  string `codec:"matchedUid" json:"matchedUid"`
+	Capability  uint8  `codec:"capability" json:"capability"`
+}
+
+type PgpExportArg struct {
+	SessionID int      `codec:"sessionID" json:"sessionID"`
+	Options   PGPQuery `codec:"options" json:"options"`
+}
+
+type PgpExportByKIDArg struct {
+	SessionID int      `codec:"sessionID" json:"sessionID"`
+	Options   PGPQuery `codec:"options" json:"options"`
+}
+
+type PgpExportByFingerprintArg struct {
+	SessionID int      `codec:"sessionID" json:"sessionID"`
+	Options   PGPQuery `codec:"options" json:"options"`
+}
+
+// PgpExportKeyringArg is the RPC argument for fetching every one of a
+// user's active public PGP keys concatenated into a single armored
+// keyring, mirroring the GitHub/Gitea `/user.gpg` convention.
+type PgpExportKeyringArg struct {
+	SessionID int      `codec:"sessionID" json:"sessionID"`
+	Options   PGPQuery `codec:"options" json:"options"`
+}
+
+// PgpExportStreamArg is PgpExportKeyringArg for the streaming RPC: the
+// server sends the keyring back as a sequence of chunk notifications
+// instead of one reply.
+type PgpExportStreamArg struct {
+	SessionID int      `codec:"sessionID" json:"sessionID"`
+	Options   PGPQuery `codec:"options" json:"options"`
+}
+
+// PgpExportByEmailArg matches against a key's identity email addresses.
+type PgpExportByEmailArg struct {
+	SessionID int      `codec:"sessionID" json:"sessionID"`
+	Options   PGPQuery `codec:"options" json:"options"`
+}
+
+// PgpExportByUIDArg matches against a key's full identity UID string (e.g.
+// "Alice <alice@keybase.io>").
+type PgpExportByUIDArg struct {
+	SessionID int      `codec:"sessionID" json:"sessionID"`
+	Options   PGPQuery `codec:"options" json:"options"`
+}
+
+// PgpExportByCapabilityArg matches against the sign/encrypt/certify/
+// authenticate capability bits on a key's identity or subkey signatures.
+type PgpExportByCapabilityArg struct {
+	SessionID int      `codec:"sessionID" json:"sessionID"`
+	Options   PGPQuery `codec:"options" json:"options"`
+}
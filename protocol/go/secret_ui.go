@@ -0,0 +1,20 @@
+package keybase1
+
+// GUIEntryArg describes a passphrase prompt for SecretUI.GetPassphrase to
+// show the user.
+type GUIEntryArg struct {
+	Prompt string `codec:"prompt" json:"prompt"`
+}
+
+// SecretEntryArg is the terminal-based fallback prompt GetPassphrase
+// accepts alongside GUIEntryArg. Callers with no terminal fallback pass
+// nil.
+type SecretEntryArg struct {
+	Prompt string `codec:"prompt" json:"prompt"`
+}
+
+// GetPassphraseRes is what a SecretUI implementation returns from
+// GetPassphrase.
+type GetPassphraseRes struct {
+	Passphrase string `codec:"passphrase" json:"passphrase"`
+}